@@ -0,0 +1,75 @@
+// Package httpapi exposes a store.Store over HTTP so other services
+// can query decoded mints, distributions, and transfers without
+// talking to the database directly.
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/Plunderswap/kusd-data/store"
+)
+
+// NewServer returns an *http.ServeMux exposing s under /mints,
+// /distributions, and /transfers. Each endpoint accepts optional
+// from, to, and since (RFC3339) query parameters.
+func NewServer(s store.Store) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mints", queryHandler(s, store.KindMint))
+	mux.HandleFunc("/distributions", queryHandler(s, store.KindDistribution))
+	mux.HandleFunc("/transfers", queryHandler(s, store.KindTransfer))
+	return mux
+}
+
+func queryHandler(s store.Store, kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		records, err := s.Query(r.Context(), kind, filter)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(records); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+func parseFilter(r *http.Request) (store.Filter, error) {
+	var filter store.Filter
+
+	if from := r.URL.Query().Get("from"); from != "" {
+		if !common.IsHexAddress(from) {
+			return store.Filter{}, fmt.Errorf("invalid from address %q", from)
+		}
+		addr := common.HexToAddress(from)
+		filter.From = &addr
+	}
+	if to := r.URL.Query().Get("to"); to != "" {
+		if !common.IsHexAddress(to) {
+			return store.Filter{}, fmt.Errorf("invalid to address %q", to)
+		}
+		addr := common.HexToAddress(to)
+		filter.To = &addr
+	}
+	if since := r.URL.Query().Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return store.Filter{}, err
+		}
+		filter.Since = t
+	}
+
+	return filter, nil
+}
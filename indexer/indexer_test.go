@@ -0,0 +1,196 @@
+package indexer
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func loadTestWatches(t *testing.T) []Watch {
+	t.Helper()
+	watches, err := LoadConfig("../config.yaml")
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	return watches
+}
+
+func watchNamed(t *testing.T, watches []Watch, name string) Watch {
+	t.Helper()
+	for _, w := range watches {
+		if w.Name == name {
+			return w
+		}
+	}
+	t.Fatalf("no watch named %q in config", name)
+	return Watch{}
+}
+
+func mustPack(t *testing.T, w Watch, method string, args ...interface{}) []byte {
+	t.Helper()
+	packed, err := w.ABI.Pack(method, args...)
+	if err != nil {
+		t.Fatalf("packing %s: %v", method, err)
+	}
+	return packed
+}
+
+func TestDecodeCall(t *testing.T) {
+	watches := loadTestWatches(t)
+	kusd := watchNamed(t, watches, "kusd")
+	distributor := watchNamed(t, watches, "distributor")
+
+	receiver := common.HexToAddress("0x000000000000000000000000000000000000aa")
+	amount := big.NewInt(1_000_000)
+
+	t.Run("watched method decodes", func(t *testing.T) {
+		input := mustPack(t, kusd, "mint", receiver, amount)
+		call, err := DecodeCall(input, kusd)
+		if err != nil {
+			t.Fatalf("DecodeCall: %v", err)
+		}
+		if call.Method != "mint" {
+			t.Errorf("Method = %q, want %q", call.Method, "mint")
+		}
+		if got, _ := call.Args["_receiver"].(common.Address); got != receiver {
+			t.Errorf("_receiver = %s, want %s", got, receiver)
+		}
+		if got, _ := call.Args["_amount"].(*big.Int); got == nil || got.Cmp(amount) != 0 {
+			t.Errorf("_amount = %v, want %v", got, amount)
+		}
+	})
+
+	t.Run("second watch, different method", func(t *testing.T) {
+		input := mustPack(t, distributor, "depositToken1Distribution", amount)
+		call, err := DecodeCall(input, distributor)
+		if err != nil {
+			t.Fatalf("DecodeCall: %v", err)
+		}
+		if call.Method != "depositToken1Distribution" {
+			t.Errorf("Method = %q, want %q", call.Method, "depositToken1Distribution")
+		}
+	})
+
+	t.Run("known selector but not watched on this contract", func(t *testing.T) {
+		input := mustPack(t, kusd, "mint", receiver, amount)
+		if _, err := DecodeCall(input, distributor); err == nil {
+			t.Fatal("expected an error decoding kusd's mint selector against distributor's ABI")
+		}
+	})
+
+	t.Run("unknown selector", func(t *testing.T) {
+		input := append([]byte{0xde, 0xad, 0xbe, 0xef}, make([]byte, 32)...)
+		if _, err := DecodeCall(input, kusd); err == nil {
+			t.Fatal("expected an error for an unknown selector")
+		}
+	})
+
+	t.Run("input too short", func(t *testing.T) {
+		if _, err := DecodeCall([]byte{0x01, 0x02}, kusd); err == nil {
+			t.Fatal("expected an error for input shorter than a selector")
+		}
+	})
+
+	t.Run("method not in watched set", func(t *testing.T) {
+		unwatched := kusd
+		unwatched.Methods = map[string]bool{}
+		input := mustPack(t, kusd, "mint", receiver, amount)
+		if _, err := DecodeCall(input, unwatched); err == nil {
+			t.Fatal("expected an error when the method isn't in Watch.Methods")
+		}
+	})
+}
+
+func mustPackLog(t *testing.T, w Watch, event string, indexedArgs []common.Hash, data ...interface{}) types.Log {
+	t.Helper()
+	ev, ok := w.ABI.Events[event]
+	if !ok {
+		t.Fatalf("no event %q in ABI", event)
+	}
+
+	var nonIndexed abi.Arguments
+	for _, arg := range ev.Inputs {
+		if !arg.Indexed {
+			nonIndexed = append(nonIndexed, arg)
+		}
+	}
+	packedData, err := nonIndexed.Pack(data...)
+	if err != nil {
+		t.Fatalf("packing event data: %v", err)
+	}
+
+	topics := append([]common.Hash{ev.ID}, indexedArgs...)
+	return types.Log{Topics: topics, Data: packedData}
+}
+
+func TestDecodeLog(t *testing.T) {
+	watches := loadTestWatches(t)
+	kusd := watchNamed(t, watches, "kusd")
+	distributor := watchNamed(t, watches, "distributor")
+
+	from := common.HexToAddress("0x000000000000000000000000000000000000aa")
+	to := common.HexToAddress("0x000000000000000000000000000000000000bb")
+	value := big.NewInt(42_000_000)
+
+	t.Run("watched event decodes", func(t *testing.T) {
+		l := mustPackLog(t, kusd, "Transfer", []common.Hash{
+			common.BytesToHash(from.Bytes()),
+			common.BytesToHash(to.Bytes()),
+		}, value)
+
+		event, err := DecodeLog(l, kusd)
+		if err != nil {
+			t.Fatalf("DecodeLog: %v", err)
+		}
+		if event.Name != "Transfer" {
+			t.Errorf("Name = %q, want %q", event.Name, "Transfer")
+		}
+		if got, _ := event.Args["from"].(common.Address); got != from {
+			t.Errorf("from = %s, want %s", got, from)
+		}
+		if got, _ := event.Args["to"].(common.Address); got != to {
+			t.Errorf("to = %s, want %s", got, to)
+		}
+		if got, _ := event.Args["value"].(*big.Int); got == nil || got.Cmp(value) != 0 {
+			t.Errorf("value = %v, want %v", got, value)
+		}
+	})
+
+	t.Run("event not watched on this contract", func(t *testing.T) {
+		l := mustPackLog(t, kusd, "Transfer", []common.Hash{
+			common.BytesToHash(from.Bytes()),
+			common.BytesToHash(to.Bytes()),
+		}, value)
+		if _, err := DecodeLog(l, distributor); err == nil {
+			t.Fatal("expected an error for a topic the distributor watch doesn't know about")
+		}
+	})
+
+	t.Run("unknown topic", func(t *testing.T) {
+		l := types.Log{Topics: []common.Hash{common.BytesToHash([]byte("not a real event"))}}
+		if _, err := DecodeLog(l, kusd); err == nil {
+			t.Fatal("expected an error for an unknown topic0")
+		}
+	})
+
+	t.Run("no topics", func(t *testing.T) {
+		if _, err := DecodeLog(types.Log{}, kusd); err == nil {
+			t.Fatal("expected an error for a log with no topics")
+		}
+	})
+
+	t.Run("event not in watched set", func(t *testing.T) {
+		unwatched := kusd
+		unwatched.Events = map[string]bool{}
+		l := mustPackLog(t, kusd, "Transfer", []common.Hash{
+			common.BytesToHash(from.Bytes()),
+			common.BytesToHash(to.Bytes()),
+		}, value)
+		if _, err := DecodeLog(l, unwatched); err == nil {
+			t.Fatal("expected an error when the event isn't in Watch.Events")
+		}
+	})
+}
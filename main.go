@@ -1,222 +1,321 @@
-package main
-
-import (
-	"fmt"
-	"log"
-	"math/big"
-	"strings"
-	"time"
-
-	"github.com/ethereum/go-ethereum/accounts/abi"
-	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/rpc"
-)
-
-const (
-	rpcEndpoint = "https://mainnet-v934-fireblocks.mainnet-20240103-ase1.zq1.network"
-
-	contractAddress1 = "0xE9df5b4b1134A3aadf693Db999786699B016239e"
-	mintAction       = "0x40C10F19"
-
-	contractAddress2                = "0x7bAefF8996101048Ba905dB8695C8f77ae4e7631"
-	depositToken1DistributionAction = "0x0800BA03"
-
-	tokenOfInterest = "0xE9df5b4b1134A3aadf693Db999786699B016239e"
-)
-
-var (
-	transferEventSig = []byte("Transfer(address,address,uint256)")
-	transferTopic    = common.BytesToHash(transferEventSig)
-
-	mintABI, _ = abi.JSON(strings.NewReader(`[{"inputs":[{"internalType":"address","name":"_receiver","type":"address"},{"internalType":"uint256","name":"_amount","type":"uint256"}],"name":"mint","outputs":[],"stateMutability":"nonpayable","type":"function"}]`))
-
-	depositToken1DistributionABI, _ = abi.JSON(strings.NewReader(`[
-		{
-			"inputs": [
-				{"internalType": "uint256", "name": "amount", "type": "uint256"}
-			],
-			"name": "depositToken1Distribution",
-			"outputs": [],
-			"stateMutability": "nonpayable",
-			"type": "function"
-		}
-	]`))
-)
-
-func main() {
-	client, err := rpc.Dial(rpcEndpoint)
-	if err != nil {
-		log.Fatalf("Failed to connect to the Ethereum client: %v", err)
-	}
-	defer client.Close()
-
-	// Get the latest block number
-	var latestBlockNumber string
-	err = client.Call(&latestBlockNumber, "eth_blockNumber")
-	if err != nil {
-		log.Fatalf("Error fetching latest block number: %v", err)
-	}
-	endBlock, _ := hexutil.DecodeUint64(latestBlockNumber)
-
-	// Calculate the start block (approximately 30 days ago)
-	// Assuming an average block time of 15 seconds
-	blocksPerDay := uint64(24 * 60 * 60 / 30)
-	startBlock := endBlock - (30 * blocksPerDay)
-
-	fmt.Printf("Searching from block %d to %d\n", startBlock, endBlock)
-
-	searchBlocks(client, startBlock, endBlock)
-}
-
-func searchBlocks(client *rpc.Client, startBlock, endBlock uint64) {
-	for blockNumber := endBlock; blockNumber >= startBlock; blockNumber-- {
-		if blockNumber%1000 == 0 {
-			fmt.Printf("Processing block %d\n", blockNumber)
-		}
-
-		var block map[string]interface{}
-		err := client.Call(&block, "eth_getBlockByNumber", hexutil.EncodeUint64(blockNumber), true)
-		if err != nil {
-			log.Printf("Error fetching block %d: %v", blockNumber, err)
-			continue
-		}
-
-		transactions := block["transactions"].([]interface{})
-		for _, tx := range transactions {
-			transaction, ok := tx.(map[string]interface{})
-			if !ok {
-				continue
-			}
-
-			to, ok := transaction["to"].(string)
-			if !ok {
-				continue
-			}
-
-			if strings.EqualFold(to, contractAddress1) || strings.EqualFold(to, contractAddress2) {
-				input := transaction["input"].(string)
-				if strings.HasPrefix(strings.ToLower(input), strings.ToLower(mintAction)) ||
-					strings.HasPrefix(strings.ToLower(input), strings.ToLower(depositToken1DistributionAction)) {
-					printDetailedTransactionInfo(client, transaction, blockNumber)
-				}
-			}
-		}
-
-		// Add a small delay to avoid overwhelming the node
-		time.Sleep(50 * time.Millisecond)
-	}
-}
-
-func printDetailedTransactionInfo(client *rpc.Client, tx map[string]interface{}, blockNumber uint64) {
-	fmt.Printf("\nTransaction in block %d:\n", blockNumber)
-	fmt.Printf("Hash: %s\n", tx["hash"])
-	fmt.Printf("From: %s\n", tx["from"])
-	fmt.Printf("To: %s\n", tx["to"])
-	// fmt.Printf("Value: %s\n", tx["value"])
-
-	input := tx["input"].(string)
-	// fmt.Printf("Input: %s\n", input)
-
-	if strings.HasPrefix(strings.ToLower(input), strings.ToLower(mintAction)) {
-		fmt.Println("This is a mint transaction")
-		decodeMintInput(input)
-	} else if strings.HasPrefix(strings.ToLower(input), strings.ToLower(depositToken1DistributionAction)) {
-		fmt.Println("This is a depositToken1Distribution transaction")
-		decodeDepositToken1DistributionInput(input)
-	}
-
-	var receipt map[string]interface{}
-	err := client.Call(&receipt, "eth_getTransactionReceipt", tx["hash"])
-	if err != nil {
-		log.Printf("Error getting transaction receipt: %v", err)
-		return
-	}
-
-	analyzeTransferEvents(receipt)
-
-	fmt.Println(strings.Repeat("-", 50))
-}
-
-func decodeMintInput(input string) {
-	data, err := hexutil.Decode(input)
-	if err != nil {
-		log.Printf("Error decoding input: %v", err)
-		return
-	}
-
-	method, err := mintABI.MethodById(data[:4])
-	if err != nil {
-		log.Printf("Error finding method: %v", err)
-		return
-	}
-
-	args, err := method.Inputs.Unpack(data[4:])
-	if err != nil {
-		log.Printf("Error unpacking arguments: %v", err)
-		return
-	}
-
-	receiver := args[0].(common.Address)
-	amount := new(big.Float).SetInt(args[1].(*big.Int))
-	amount.Quo(amount, big.NewFloat(1e6)) // Divide by 10^6
-
-	fmt.Printf("Mint - Receiver: %s, Amount: %.6f\n", receiver.Hex(), amount)
-}
-
-func decodeDepositToken1DistributionInput(input string) {
-	data, err := hexutil.Decode(input)
-	if err != nil {
-		log.Printf("Error decoding input: %v", err)
-		return
-	}
-
-	method, err := depositToken1DistributionABI.MethodById(data[:4])
-	if err != nil {
-		log.Printf("Error finding method with id %s: %v", hexutil.Encode(data[:4]), err)
-		return
-	}
-
-	args, err := method.Inputs.Unpack(data[4:])
-	if err != nil {
-		log.Printf("Error unpacking arguments: %v", err)
-		return
-	}
-
-	amount := new(big.Float).SetInt(args[0].(*big.Int))
-	amount.Quo(amount, big.NewFloat(1e6)) // Divide by 10^6
-
-	fmt.Printf("DepositToken1Distribution - Amount: %.6f\n", amount)
-}
-
-func analyzeTransferEvents(receipt map[string]interface{}) {
-	logs, ok := receipt["logs"].([]interface{})
-	if !ok {
-		log.Println("No logs found in receipt")
-		return
-	}
-
-	for _, logEntry := range logs {
-		log, ok := logEntry.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		topics, ok := log["topics"].([]interface{})
-		if !ok || len(topics) < 3 {
-			continue
-		}
-
-		if topics[0].(string) == transferTopic.Hex() && strings.EqualFold(log["address"].(string), tokenOfInterest) {
-			from := common.HexToAddress(topics[1].(string)).Hex()
-			to := common.HexToAddress(topics[2].(string)).Hex()
-			amount := new(big.Int)
-			amount.SetString(log["data"].(string)[2:], 16)
-
-			amountFloat := new(big.Float).SetInt(amount)
-			amountFloat.Quo(amountFloat, big.NewFloat(1e6)) // Divide by 10^6
-
-			fmt.Printf("Token Transfer - From: %s, To: %s, Amount: %.6f\n", from, to, amountFloat)
-		}
-	}
-}
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/Plunderswap/kusd-data/httpapi"
+	"github.com/Plunderswap/kusd-data/indexer"
+	"github.com/Plunderswap/kusd-data/scanner"
+	"github.com/Plunderswap/kusd-data/store"
+	"github.com/Plunderswap/kusd-data/subscriber"
+	"github.com/Plunderswap/kusd-data/units"
+)
+
+const (
+	rpcEndpoint = "https://mainnet-v934-fireblocks.mainnet-20240103-ase1.zq1.network"
+	wsEndpoint  = "wss://mainnet-v934-fireblocks.mainnet-20240103-ase1.zq1.network/ws"
+)
+
+var (
+	transferEventSig = []byte("Transfer(address,address,uint256)")
+	transferTopic    = crypto.Keccak256Hash(transferEventSig)
+)
+
+func main() {
+	configPath := flag.String("config", "config.yaml", "path to the watch config file")
+	follow := flag.Bool("follow", false, "stream new matches over eth_subscribe instead of scanning history")
+	confirmations := flag.Uint64("confirmations", 6, "blocks to wait before treating a --follow match as final")
+	dbPath := flag.String("db", "kusd.db", "path to the SQLite database file")
+	postgresDSN := flag.String("postgres", "", "Postgres DSN; overrides -db when set")
+	httpAddr := flag.String("http", "", "address to serve the /mints, /distributions, /transfers API on, e.g. :8080 (disabled if empty)")
+	workers := flag.Int("workers", 8, "concurrent range workers for the historical scan")
+	rangeSize := flag.Uint64("range-size", 2000, "blocks covered per eth_getLogs call")
+	rateLimit := flag.Duration("rate-limit", 50*time.Millisecond, "minimum spacing between RPC calls, shared across workers")
+	flag.Parse()
+
+	watches, err := indexer.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config %s: %v", *configPath, err)
+	}
+
+	s, err := openStore(*dbPath, *postgresDSN)
+	if err != nil {
+		log.Fatalf("Failed to open store: %v", err)
+	}
+	defer s.Close()
+
+	if *httpAddr != "" {
+		go serveHTTP(*httpAddr, s)
+	}
+
+	if *follow {
+		followMatches(watches, *confirmations, s)
+		return
+	}
+
+	client, err := rpc.Dial(rpcEndpoint)
+	if err != nil {
+		log.Fatalf("Failed to connect to the Ethereum client: %v", err)
+	}
+	defer client.Close()
+
+	// Get the latest block number
+	var latestBlockNumber string
+	err = client.Call(&latestBlockNumber, "eth_blockNumber")
+	if err != nil {
+		log.Fatalf("Error fetching latest block number: %v", err)
+	}
+	endBlock, _ := hexutil.DecodeUint64(latestBlockNumber)
+
+	startBlock, err := resumeBlock(s, endBlock)
+	if err != nil {
+		log.Fatalf("Failed to read last scanned block: %v", err)
+	}
+
+	fmt.Printf("Searching from block %d to %d\n", startBlock, endBlock)
+
+	if err := searchBlocks(client, startBlock, endBlock, watches, s, *workers, *rangeSize, *rateLimit); err != nil {
+		// scanner.Config.Progress already checkpointed everything
+		// scanned successfully before the failure; don't mark the
+		// unscanned tail as done.
+		log.Fatalf("Error scanning blocks: %v", err)
+	}
+
+	if err := s.SetLastScannedBlock(context.Background(), endBlock); err != nil {
+		log.Printf("Error saving last scanned block: %v", err)
+	}
+}
+
+func openStore(dbPath, postgresDSN string) (store.Store, error) {
+	if postgresDSN != "" {
+		return store.NewPostgresStore(postgresDSN)
+	}
+	return store.NewSQLiteStore(dbPath)
+}
+
+// resumeBlock picks up where the previous run left off, falling back
+// to an approximately-30-day window ending at endBlock (at a 30 second
+// average block time) when nothing has been scanned before.
+func resumeBlock(s store.Store, endBlock uint64) (uint64, error) {
+	last, ok, err := s.LastScannedBlock(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		return last + 1, nil
+	}
+
+	blocksPerDay := uint64(24 * 60 * 60 / 30)
+	return endBlock - (30 * blocksPerDay), nil
+}
+
+func serveHTTP(addr string, s store.Store) {
+	fmt.Printf("Serving API on %s\n", addr)
+	if err := http.ListenAndServe(addr, httpapi.NewServer(s)); err != nil {
+		log.Printf("HTTP server stopped: %v", err)
+	}
+}
+
+// followMatches opens a websocket subscription for watches and prints
+// and persists each confirmed (or reorg-corrected) log as it arrives.
+func followMatches(watches []indexer.Watch, confirmations uint64, s store.Store) {
+	ctx := context.Background()
+
+	client, err := rpc.DialContext(ctx, wsEndpoint)
+	if err != nil {
+		log.Fatalf("Failed to connect to the Ethereum websocket endpoint: %v", err)
+	}
+	defer client.Close()
+
+	addresses := make([]common.Address, len(watches))
+	for i, w := range watches {
+		addresses[i] = w.Address
+	}
+
+	sub, err := subscriber.New(ctx, subscriber.Config{
+		Client:        client,
+		Addresses:     addresses,
+		Topics:        []common.Hash{transferTopic},
+		Confirmations: confirmations,
+	})
+	if err != nil {
+		log.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer sub.Close()
+
+	fmt.Println("Following new matches...")
+	for event := range sub.Events() {
+		w := watchFor(watches, event.Log.Address)
+		if w == nil {
+			continue
+		}
+		decoded, err := indexer.DecodeLog(event.Log, *w)
+		if err != nil {
+			continue
+		}
+		if event.Removed {
+			fmt.Printf("[REORG] retracting %s %s at block %d\n", w.Name, decoded.Name, event.Log.BlockNumber)
+			continue
+		}
+		printTransfer(*w, decoded)
+		if err := s.PutTransfer(ctx, transferRecord(*w, decoded, event.Log)); err != nil {
+			log.Printf("Error persisting transfer: %v", err)
+		}
+	}
+	if err := sub.Err(); err != nil {
+		log.Fatalf("Subscription stopped: %v", err)
+	}
+}
+
+func searchBlocks(client *rpc.Client, startBlock, endBlock uint64, watches []indexer.Watch, s store.Store, workers int, rangeSize uint64, rateLimit time.Duration) error {
+	addresses := make([]common.Address, len(watches))
+	for i, w := range watches {
+		addresses[i] = w.Address
+	}
+
+	cfg := scanner.Config{
+		Client:     client,
+		StartBlock: startBlock,
+		EndBlock:   endBlock,
+		Addresses:  addresses,
+		Topics:     []common.Hash{transferTopic},
+		Workers:    workers,
+		RangeSize:  rangeSize,
+		RateLimit:  rateLimit,
+		Progress: func(throughBlock uint64) {
+			if err := s.SetLastScannedBlock(context.Background(), throughBlock); err != nil {
+				log.Printf("Error checkpointing last scanned block %d: %v", throughBlock, err)
+			}
+		},
+	}
+
+	matches, err := scanner.Scan(context.Background(), cfg)
+
+	for _, match := range matches {
+		w := watchFor(watches, match.Log.Address)
+		if w == nil {
+			continue
+		}
+		printDetailedTransactionInfo(match.Transaction, match.Log, match.BlockNumber, *w, s)
+	}
+
+	return err
+}
+
+func watchFor(watches []indexer.Watch, address common.Address) *indexer.Watch {
+	for i, w := range watches {
+		if w.Address == address {
+			return &watches[i]
+		}
+	}
+	return nil
+}
+
+func printDetailedTransactionInfo(tx map[string]interface{}, l types.Log, blockNumber uint64, w indexer.Watch, s store.Store) {
+	fmt.Printf("\nTransaction in block %d:\n", blockNumber)
+	fmt.Printf("Hash: %s\n", tx["hash"])
+	fmt.Printf("From: %s\n", tx["from"])
+	fmt.Printf("To: %s\n", tx["to"])
+
+	ctx := context.Background()
+
+	input, err := hexutil.Decode(fmt.Sprint(tx["input"]))
+	if err != nil {
+		log.Printf("Error decoding input: %v", err)
+		return
+	}
+
+	if call, err := indexer.DecodeCall(input, w); err == nil {
+		printCall(w, call)
+		record := callRecord(w, call, tx, l)
+		if call.Method == "mint" {
+			err = s.PutMint(ctx, record)
+		} else {
+			err = s.PutDistribution(ctx, record)
+		}
+		if err != nil {
+			log.Printf("Error persisting %s: %v", call.Method, err)
+		}
+	}
+
+	if event, err := indexer.DecodeLog(l, w); err == nil {
+		printTransfer(w, event)
+		if err := s.PutTransfer(ctx, transferRecord(w, event, l)); err != nil {
+			log.Printf("Error persisting transfer: %v", err)
+		}
+	}
+
+	fmt.Println("--------------------------------------------------")
+}
+
+func printCall(w indexer.Watch, call indexer.Call) {
+	amount, _ := call.Args["amount"].(*big.Int)
+	if amount == nil {
+		amount, _ = call.Args["_amount"].(*big.Int)
+	}
+	fmt.Printf("%s %s - %s\n", w.Name, call.Method, units.Format(amount, w.Decimals))
+}
+
+func printTransfer(w indexer.Watch, event indexer.Event) {
+	from, _ := event.Args["from"].(common.Address)
+	to, _ := event.Args["to"].(common.Address)
+	value, _ := event.Args["value"].(*big.Int)
+	fmt.Printf("%s Transfer - From: %s, To: %s, Amount: %s\n", w.Name, from.Hex(), to.Hex(), units.Format(value, w.Decimals))
+}
+
+// callRecord turns a decoded mint/distribution call into a store
+// Record. There's no "from" in the call arguments themselves, so we
+// use the transaction sender; the receiver comes from the call args
+// when the method has one (mint), and falls back to the watched
+// contract itself (distributions credit the contract, not a user).
+func callRecord(w indexer.Watch, call indexer.Call, tx map[string]interface{}, l types.Log) store.Record {
+	amount, _ := call.Args["amount"].(*big.Int)
+	if amount == nil {
+		amount, _ = call.Args["_amount"].(*big.Int)
+	}
+
+	to := w.Address
+	if receiver, ok := call.Args["_receiver"].(common.Address); ok {
+		to = receiver
+	}
+
+	return store.Record{
+		BlockNumber: l.BlockNumber,
+		TxHash:      common.HexToHash(fmt.Sprint(tx["hash"])),
+		LogIndex:    l.Index,
+		From:        common.HexToAddress(fmt.Sprint(tx["from"])),
+		To:          to,
+		Amount:      units.Format(amount, w.Decimals),
+		Method:      call.Method,
+		Timestamp:   time.Now().UTC(),
+	}
+}
+
+func transferRecord(w indexer.Watch, event indexer.Event, l types.Log) store.Record {
+	from, _ := event.Args["from"].(common.Address)
+	to, _ := event.Args["to"].(common.Address)
+	value, _ := event.Args["value"].(*big.Int)
+
+	return store.Record{
+		BlockNumber: l.BlockNumber,
+		TxHash:      l.TxHash,
+		LogIndex:    l.Index,
+		From:        from,
+		To:          to,
+		Amount:      units.Format(value, w.Decimals),
+		Method:      event.Name,
+		Timestamp:   time.Now().UTC(),
+	}
+}
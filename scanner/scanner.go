@@ -0,0 +1,358 @@
+// Package scanner walks a range of blocks looking for logs that match a
+// set of watched contract addresses and topics. It avoids the cost of
+// fetching and decoding every block by first checking each block
+// header's logsBloom filter, then only issuing eth_getLogs for the
+// ranges that could plausibly contain a match.
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Config describes a single scan: the block range to cover, the
+// contract addresses and log topics to watch for, and how aggressively
+// to parallelize against the RPC endpoint.
+type Config struct {
+	Client *rpc.Client
+
+	StartBlock uint64
+	EndBlock   uint64
+
+	// Addresses and Topics are OR'd internally by eth_getLogs, but a
+	// candidate range must have at least one bloom hit for an address
+	// AND at least one for a topic before we bother calling it.
+	Addresses []common.Address
+	Topics    []common.Hash
+
+	Workers   int           // concurrent range workers
+	RangeSize uint64        // blocks covered per eth_getLogs call
+	RateLimit time.Duration // minimum spacing between RPC calls, shared across workers
+
+	// Progress, if set, is called as ranges complete successfully, with
+	// the highest block number scanned so far with no gaps below it
+	// (an errored range is never counted, so Progress never reports
+	// past work that was actually skipped). Callers can use this to
+	// checkpoint a resume point without waiting for the whole scan.
+	Progress func(throughBlock uint64)
+}
+
+// Match is a log that survived the bloom pre-filter, was returned by
+// eth_getLogs, and has been correlated back to its transaction.
+type Match struct {
+	BlockNumber uint64
+	Log         types.Log
+	Transaction map[string]interface{}
+}
+
+const (
+	defaultWorkers   = 8
+	defaultRangeSize = 2000
+)
+
+// Scan splits [cfg.StartBlock, cfg.EndBlock] into cfg.RangeSize-block
+// chunks and fans them out across cfg.Workers goroutines. Each worker
+// first tests the logsBloom of every header in its chunk; a chunk with
+// no bloom hit is skipped entirely. Chunks that pass are queried with a
+// single eth_getLogs call, and every returned log is correlated to its
+// transaction via eth_getTransactionByHash.
+func Scan(ctx context.Context, cfg Config) ([]Match, error) {
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultWorkers
+	}
+	if cfg.RangeSize == 0 {
+		cfg.RangeSize = defaultRangeSize
+	}
+
+	limiter := newRateLimiter(cfg.RateLimit)
+	defer limiter.Stop()
+
+	boundaries := splitRanges(cfg.StartBlock, cfg.EndBlock, cfg.RangeSize)
+
+	jobs := make(chan int)
+	results := make(chan []Match)
+	errs := make(chan error, cfg.Workers)
+	done := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				r := boundaries[idx]
+				matches, err := scanRange(ctx, cfg, limiter, r[0], r[1])
+				if err != nil {
+					errs <- fmt.Errorf("scanning range %d-%d: %w", r[0], r[1], err)
+					continue
+				}
+				if len(matches) > 0 {
+					results <- matches
+				}
+				done <- idx
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for idx := range boundaries {
+			select {
+			case jobs <- idx:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+		close(errs)
+		close(done)
+	}()
+
+	// results, errs, and done must all be drained concurrently: workers
+	// block on whichever of the three fills up first, so waiting on one
+	// channel before touching the others can deadlock once the number
+	// of outstanding sends exceeds its buffer.
+	var all []Match
+	var firstErr error
+	var collect sync.WaitGroup
+	collect.Add(3)
+	go func() {
+		defer collect.Done()
+		for matches := range results {
+			all = append(all, matches...)
+		}
+	}()
+	go func() {
+		defer collect.Done()
+		for err := range errs {
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}()
+	go func() {
+		defer collect.Done()
+		reportContiguousProgress(cfg, boundaries, done)
+	}()
+	collect.Wait()
+
+	if firstErr != nil {
+		return all, firstErr
+	}
+	return all, ctx.Err()
+}
+
+// splitRanges divides [start, end] into consecutive, non-overlapping
+// [from, to] chunks of at most size blocks each.
+func splitRanges(start, end, size uint64) [][2]uint64 {
+	var ranges [][2]uint64
+	for from := start; from <= end; from += size {
+		to := from + size - 1
+		if to > end {
+			to = end
+		}
+		ranges = append(ranges, [2]uint64{from, to})
+	}
+	return ranges
+}
+
+// reportContiguousProgress reads completed range indexes off done (in
+// whatever order workers finish them) and calls cfg.Progress with the
+// end block of the longest unbroken prefix of boundaries completed so
+// far, so a checkpoint is never advanced past a range that is still in
+// flight or failed.
+func reportContiguousProgress(cfg Config, boundaries [][2]uint64, done <-chan int) {
+	if cfg.Progress == nil {
+		for range done {
+		}
+		return
+	}
+
+	finished := make([]bool, len(boundaries))
+	next := 0
+	for idx := range done {
+		finished[idx] = true
+		for next < len(boundaries) && finished[next] {
+			next++
+		}
+		if next > 0 {
+			cfg.Progress(boundaries[next-1][1])
+		}
+	}
+}
+
+// scanRange tests the header blooms for [start, end] and, if any could
+// contain a watched address and topic, pulls the matching logs with
+// eth_getLogs and resolves each one's transaction.
+func scanRange(ctx context.Context, cfg Config, limiter *rateLimiter, start, end uint64) ([]Match, error) {
+	candidate, err := rangeMayMatch(ctx, cfg, limiter, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("checking bloom filters: %w", err)
+	}
+	if !candidate {
+		return nil, nil
+	}
+
+	logs, err := getLogs(ctx, cfg, limiter, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("eth_getLogs: %w", err)
+	}
+
+	matches := make([]Match, 0, len(logs))
+	for _, l := range logs {
+		tx, err := getTransaction(ctx, cfg.Client, limiter, l.TxHash)
+		if err != nil {
+			return nil, fmt.Errorf("eth_getTransactionByHash(%s): %w", l.TxHash, err)
+		}
+		matches = append(matches, Match{
+			BlockNumber: l.BlockNumber,
+			Log:         l,
+			Transaction: tx,
+		})
+	}
+	return matches, nil
+}
+
+// rangeMayMatch fetches the header of every block in [start, end] and
+// tests its logsBloom against cfg.Addresses and cfg.Topics. It returns
+// true as soon as a single header could contain both a watched address
+// and a watched topic.
+func rangeMayMatch(ctx context.Context, cfg Config, limiter *rateLimiter, start, end uint64) (bool, error) {
+	for blockNumber := start; blockNumber <= end; blockNumber++ {
+		limiter.Wait(ctx)
+
+		var header struct {
+			LogsBloom hexutil.Bytes `json:"logsBloom"`
+		}
+		err := cfg.Client.CallContext(ctx, &header, "eth_getBlockByNumber", hexutil.EncodeUint64(blockNumber), false)
+		if err != nil {
+			return false, fmt.Errorf("eth_getBlockByNumber(%d): %w", blockNumber, err)
+		}
+
+		bloom := types.BytesToBloom(header.LogsBloom)
+		if bloomMayMatch(bloom, cfg.Addresses, cfg.Topics) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// bloomMayMatch reports whether bloom could contain a log from any of
+// addrs that carries any of topics. False positives are expected (that
+// is what a bloom filter is); false negatives are not, which is what
+// makes it safe to use as a skip filter.
+func bloomMayMatch(bloom types.Bloom, addrs []common.Address, topics []common.Hash) bool {
+	addrHit := len(addrs) == 0
+	for _, a := range addrs {
+		if bloom.Test(a.Bytes()) {
+			addrHit = true
+			break
+		}
+	}
+	if !addrHit {
+		return false
+	}
+
+	topicHit := len(topics) == 0
+	for _, t := range topics {
+		if bloom.Test(t.Bytes()) {
+			topicHit = true
+			break
+		}
+	}
+	return topicHit
+}
+
+// getLogs issues a single eth_getLogs call covering [start, end] for
+// cfg.Addresses and cfg.Topics.
+func getLogs(ctx context.Context, cfg Config, limiter *rateLimiter, start, end uint64) ([]types.Log, error) {
+	limiter.Wait(ctx)
+
+	filter := map[string]interface{}{
+		"fromBlock": hexutil.EncodeUint64(start),
+		"toBlock":   hexutil.EncodeUint64(end),
+		"address":   cfg.Addresses,
+		"topics":    [][]common.Hash{cfg.Topics},
+	}
+
+	var logs []types.Log
+	if err := cfg.Client.CallContext(ctx, &logs, "eth_getLogs", filter); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// getTransaction fetches the full transaction for hash, used to
+// correlate a confirmed log hit back to its calldata.
+func getTransaction(ctx context.Context, client *rpc.Client, limiter *rateLimiter, hash common.Hash) (map[string]interface{}, error) {
+	limiter.Wait(ctx)
+
+	var tx map[string]interface{}
+	if err := client.CallContext(ctx, &tx, "eth_getTransactionByHash", hash); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// rateLimiter hands out one token per interval, shared across all
+// workers, so a scan with many goroutines still issues RPC calls at a
+// bounded rate.
+type rateLimiter struct {
+	tokens chan struct{}
+	stop   chan struct{}
+}
+
+func newRateLimiter(interval time.Duration) *rateLimiter {
+	rl := &rateLimiter{
+		tokens: make(chan struct{}),
+		stop:   make(chan struct{}),
+	}
+	if interval <= 0 {
+		close(rl.tokens) // reading from a closed channel never blocks: unlimited rate
+		return rl
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				select {
+				case rl.tokens <- struct{}{}:
+				case <-rl.stop:
+					return
+				}
+			case <-rl.stop:
+				return
+			}
+		}
+	}()
+	return rl
+}
+
+func (rl *rateLimiter) Wait(ctx context.Context) {
+	select {
+	case <-rl.tokens:
+	case <-ctx.Done():
+	}
+}
+
+func (rl *rateLimiter) Stop() {
+	select {
+	case <-rl.stop:
+	default:
+		close(rl.stop)
+	}
+}
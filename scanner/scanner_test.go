@@ -0,0 +1,118 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestSplitRanges(t *testing.T) {
+	tests := []struct {
+		name             string
+		start, end, size uint64
+		want             [][2]uint64
+	}{
+		{"single range smaller than size", 0, 5, 10, [][2]uint64{{0, 5}}},
+		{"exact multiple", 0, 19, 10, [][2]uint64{{0, 9}, {10, 19}}},
+		{"with remainder", 0, 25, 10, [][2]uint64{{0, 9}, {10, 19}, {20, 25}}},
+		{"start equals end", 7, 7, 10, [][2]uint64{{7, 7}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitRanges(tt.start, tt.end, tt.size)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitRanges(%d, %d, %d) = %v, want %v", tt.start, tt.end, tt.size, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBloomMayMatch(t *testing.T) {
+	addr := common.HexToAddress("0x000000000000000000000000000000000000aa")
+	other := common.HexToAddress("0x000000000000000000000000000000000000bb")
+	topic := common.HexToHash("0x01")
+	otherTopic := common.HexToHash("0x02")
+
+	var bloom types.Bloom
+	bloom.Add(addr.Bytes())
+	bloom.Add(topic.Bytes())
+
+	tests := []struct {
+		name   string
+		addrs  []common.Address
+		topics []common.Hash
+		want   bool
+	}{
+		{"address and topic both present", []common.Address{addr}, []common.Hash{topic}, true},
+		{"address present, topic absent", []common.Address{addr}, []common.Hash{otherTopic}, false},
+		{"address absent, topic present", []common.Address{other}, []common.Hash{topic}, false},
+		{"both absent", []common.Address{other}, []common.Hash{otherTopic}, false},
+		{"no addresses filters by topic only", nil, []common.Hash{topic}, true},
+		{"no topics filters by address only", []common.Address{addr}, nil, true},
+		{"neither filter always matches", nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bloomMayMatch(bloom, tt.addrs, tt.topics); got != tt.want {
+				t.Errorf("bloomMayMatch(...) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportContiguousProgress(t *testing.T) {
+	boundaries := [][2]uint64{{0, 9}, {10, 19}, {20, 29}, {30, 39}}
+
+	var reported []uint64
+	cfg := Config{Progress: func(throughBlock uint64) { reported = append(reported, throughBlock) }}
+
+	done := make(chan int, len(boundaries))
+	// Finish out of order: 1, 0, then 2, 3. The gap at 0 blocks progress
+	// past range 1 until it arrives.
+	done <- 1
+	done <- 0
+	done <- 2
+	done <- 3
+	close(done)
+
+	reportContiguousProgress(cfg, boundaries, done)
+
+	want := []uint64{19, 29, 39}
+	if !reflect.DeepEqual(reported, want) {
+		t.Errorf("reported progress = %v, want %v", reported, want)
+	}
+}
+
+func TestReportContiguousProgressStopsAtGap(t *testing.T) {
+	boundaries := [][2]uint64{{0, 9}, {10, 19}, {20, 29}}
+
+	var reported []uint64
+	cfg := Config{Progress: func(throughBlock uint64) { reported = append(reported, throughBlock) }}
+
+	done := make(chan int, 2)
+	// Range 1 finishes but range 0 never does: progress must never
+	// advance past a gap, even though a later range is done.
+	done <- 1
+	done <- 2
+	close(done)
+
+	reportContiguousProgress(cfg, boundaries, done)
+
+	if len(reported) != 0 {
+		t.Errorf("reported progress = %v, want none (range 0 never completed)", reported)
+	}
+}
+
+func TestReportContiguousProgressNilCallback(t *testing.T) {
+	boundaries := [][2]uint64{{0, 9}}
+	done := make(chan int, 1)
+	done <- 0
+	close(done)
+
+	// Must drain done without calling Progress or panicking.
+	reportContiguousProgress(Config{}, boundaries, done)
+}
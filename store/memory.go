@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore is an in-memory Store, used in tests where nothing
+// needs to survive a restart.
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	records          map[string][]Record
+	lastScannedBlock uint64
+	hasScanned       bool
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string][]Record)}
+}
+
+func (s *MemoryStore) PutMint(ctx context.Context, r Record) error {
+	return s.put(KindMint, r)
+}
+
+func (s *MemoryStore) PutDistribution(ctx context.Context, r Record) error {
+	return s.put(KindDistribution, r)
+}
+
+func (s *MemoryStore) PutTransfer(ctx context.Context, r Record) error {
+	return s.put(KindTransfer, r)
+}
+
+func (s *MemoryStore) put(kind string, r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[kind] = append(s.records[kind], r)
+	return nil
+}
+
+func (s *MemoryStore) LastScannedBlock(ctx context.Context) (uint64, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastScannedBlock, s.hasScanned, nil
+}
+
+func (s *MemoryStore) SetLastScannedBlock(ctx context.Context, block uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastScannedBlock = block
+	s.hasScanned = true
+	return nil
+}
+
+func (s *MemoryStore) Query(ctx context.Context, kind string, filter Filter) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []Record
+	for _, r := range s.records[kind] {
+		if matches(r, filter) {
+			matched = append(matched, r)
+		}
+	}
+	return matched, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func matches(r Record, filter Filter) bool {
+	if filter.From != nil && r.From != *filter.From {
+		return false
+	}
+	if filter.To != nil && r.To != *filter.To {
+		return false
+	}
+	if !filter.Since.IsZero() && r.Timestamp.Before(filter.Since) {
+		return false
+	}
+	return true
+}
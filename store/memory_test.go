@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestMemoryStorePutAndQuery(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	alice := common.HexToAddress("0x000000000000000000000000000000000000aa")
+	bob := common.HexToAddress("0x000000000000000000000000000000000000bb")
+
+	mint := Record{BlockNumber: 1, From: alice, To: bob, Amount: "1", Method: "mint", Timestamp: time.Unix(100, 0)}
+	if err := s.PutMint(ctx, mint); err != nil {
+		t.Fatalf("PutMint: %v", err)
+	}
+
+	dist := Record{BlockNumber: 2, From: bob, To: alice, Amount: "2", Method: "depositToken1Distribution", Timestamp: time.Unix(200, 0)}
+	if err := s.PutDistribution(ctx, dist); err != nil {
+		t.Fatalf("PutDistribution: %v", err)
+	}
+
+	transfer := Record{BlockNumber: 3, From: alice, To: bob, Amount: "3", Method: "Transfer", Timestamp: time.Unix(300, 0)}
+	if err := s.PutTransfer(ctx, transfer); err != nil {
+		t.Fatalf("PutTransfer: %v", err)
+	}
+
+	mints, err := s.Query(ctx, KindMint, Filter{})
+	if err != nil {
+		t.Fatalf("Query(mint): %v", err)
+	}
+	if len(mints) != 1 || mints[0].BlockNumber != 1 {
+		t.Errorf("Query(mint) = %+v, want just the mint record", mints)
+	}
+
+	dists, err := s.Query(ctx, KindDistribution, Filter{})
+	if err != nil {
+		t.Fatalf("Query(distribution): %v", err)
+	}
+	if len(dists) != 1 || dists[0].BlockNumber != 2 {
+		t.Errorf("Query(distribution) = %+v, want just the distribution record", dists)
+	}
+
+	transfers, err := s.Query(ctx, KindTransfer, Filter{})
+	if err != nil {
+		t.Fatalf("Query(transfer): %v", err)
+	}
+	if len(transfers) != 1 || transfers[0].BlockNumber != 3 {
+		t.Errorf("Query(transfer) = %+v, want just the transfer record", transfers)
+	}
+}
+
+func TestMemoryStoreQueryFilters(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	alice := common.HexToAddress("0x000000000000000000000000000000000000aa")
+	bob := common.HexToAddress("0x000000000000000000000000000000000000bb")
+	carol := common.HexToAddress("0x000000000000000000000000000000000000cc")
+
+	records := []Record{
+		{BlockNumber: 1, From: alice, To: bob, Amount: "1", Method: "Transfer", Timestamp: time.Unix(100, 0)},
+		{BlockNumber: 2, From: bob, To: carol, Amount: "2", Method: "Transfer", Timestamp: time.Unix(200, 0)},
+		{BlockNumber: 3, From: alice, To: carol, Amount: "3", Method: "Transfer", Timestamp: time.Unix(300, 0)},
+	}
+	for _, r := range records {
+		if err := s.PutTransfer(ctx, r); err != nil {
+			t.Fatalf("PutTransfer: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []uint64
+	}{
+		{"no filter", Filter{}, []uint64{1, 2, 3}},
+		{"from alice", Filter{From: &alice}, []uint64{1, 3}},
+		{"to carol", Filter{To: &carol}, []uint64{2, 3}},
+		{"since block 2's timestamp", Filter{Since: time.Unix(200, 0)}, []uint64{2, 3}},
+		{"from alice to carol", Filter{From: &alice, To: &carol}, []uint64{3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Query(ctx, KindTransfer, tt.filter)
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Query returned %d records, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, r := range got {
+				if r.BlockNumber != tt.want[i] {
+					t.Errorf("record %d has BlockNumber %d, want %d", i, r.BlockNumber, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreLastScannedBlock(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	if _, ok, err := s.LastScannedBlock(ctx); err != nil || ok {
+		t.Fatalf("LastScannedBlock on a fresh store = (ok=%v, err=%v), want (false, nil)", ok, err)
+	}
+
+	if err := s.SetLastScannedBlock(ctx, 42); err != nil {
+		t.Fatalf("SetLastScannedBlock: %v", err)
+	}
+
+	block, ok, err := s.LastScannedBlock(ctx)
+	if err != nil || !ok || block != 42 {
+		t.Fatalf("LastScannedBlock = (%d, %v, %v), want (42, true, nil)", block, ok, err)
+	}
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// sqlStore is the shared implementation behind SQLiteStore and
+// PostgresStore: both talk to database/sql, differing only in driver
+// name and placeholder syntax ("?" vs "$1, $2, ..."). placeholder is
+// called once per bound argument, in order.
+type sqlStore struct {
+	db          *sql.DB
+	placeholder func(argPos int) string
+}
+
+func questionPlaceholder(int) string { return "?" }
+
+func dollarPlaceholder(argPos int) string { return "$" + strconv.Itoa(argPos) }
+
+const schema = `
+CREATE TABLE IF NOT EXISTS records (
+	kind         TEXT NOT NULL,
+	block_number INTEGER NOT NULL,
+	tx_hash      TEXT NOT NULL,
+	log_index    INTEGER NOT NULL,
+	from_address TEXT NOT NULL,
+	to_address   TEXT NOT NULL,
+	amount       TEXT NOT NULL,
+	method       TEXT NOT NULL,
+	timestamp    INTEGER NOT NULL,
+	UNIQUE (kind, tx_hash, log_index)
+);
+CREATE TABLE IF NOT EXISTS scan_progress (
+	id                 INTEGER PRIMARY KEY,
+	last_scanned_block INTEGER NOT NULL
+);
+`
+
+func newSQLStore(db *sql.DB, placeholder func(int) string) (*sqlStore, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("creating schema: %w", err)
+	}
+	return &sqlStore{db: db, placeholder: placeholder}, nil
+}
+
+// put inserts r, ignoring the call entirely if a record with the same
+// (kind, tx_hash, log_index) already exists. That's what makes
+// resuming a scan after a crash safe: the scanner may hand us a range
+// it has already (partially) persisted, and re-inserting it must be a
+// no-op rather than a duplicate row.
+func (s *sqlStore) put(ctx context.Context, kind string, r Record) error {
+	q := fmt.Sprintf(
+		`INSERT INTO records (kind, block_number, tx_hash, log_index, from_address, to_address, amount, method, timestamp)
+		 VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s)
+		 ON CONFLICT (kind, tx_hash, log_index) DO NOTHING`,
+		s.placeholder(1), s.placeholder(2), s.placeholder(3), s.placeholder(4),
+		s.placeholder(5), s.placeholder(6), s.placeholder(7), s.placeholder(8), s.placeholder(9),
+	)
+	_, err := s.db.ExecContext(ctx, q,
+		kind, r.BlockNumber, r.TxHash.Hex(), r.LogIndex,
+		r.From.Hex(), r.To.Hex(), r.Amount, r.Method, r.Timestamp.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("inserting %s record: %w", kind, err)
+	}
+	return nil
+}
+
+func (s *sqlStore) PutMint(ctx context.Context, r Record) error { return s.put(ctx, KindMint, r) }
+func (s *sqlStore) PutDistribution(ctx context.Context, r Record) error {
+	return s.put(ctx, KindDistribution, r)
+}
+func (s *sqlStore) PutTransfer(ctx context.Context, r Record) error {
+	return s.put(ctx, KindTransfer, r)
+}
+
+func (s *sqlStore) LastScannedBlock(ctx context.Context) (uint64, bool, error) {
+	var block uint64
+	err := s.db.QueryRowContext(ctx, "SELECT last_scanned_block FROM scan_progress WHERE id = 1").Scan(&block)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("reading last scanned block: %w", err)
+	}
+	return block, true, nil
+}
+
+func (s *sqlStore) SetLastScannedBlock(ctx context.Context, block uint64) error {
+	q := fmt.Sprintf(
+		`INSERT INTO scan_progress (id, last_scanned_block) VALUES (1, %s)
+		 ON CONFLICT (id) DO UPDATE SET last_scanned_block = %s`,
+		s.placeholder(1), s.placeholder(2),
+	)
+	if _, err := s.db.ExecContext(ctx, q, block, block); err != nil {
+		return fmt.Errorf("saving last scanned block: %w", err)
+	}
+	return nil
+}
+
+func (s *sqlStore) Query(ctx context.Context, kind string, filter Filter) ([]Record, error) {
+	conditions := []string{fmt.Sprintf("kind = %s", s.placeholder(1))}
+	args := []interface{}{kind}
+
+	if filter.From != nil {
+		conditions = append(conditions, fmt.Sprintf("from_address = %s", s.placeholder(len(args)+1)))
+		args = append(args, filter.From.Hex())
+	}
+	if filter.To != nil {
+		conditions = append(conditions, fmt.Sprintf("to_address = %s", s.placeholder(len(args)+1)))
+		args = append(args, filter.To.Hex())
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("timestamp >= %s", s.placeholder(len(args)+1)))
+		args = append(args, filter.Since.Unix())
+	}
+
+	q := "SELECT block_number, tx_hash, log_index, from_address, to_address, amount, method, timestamp FROM records WHERE " +
+		strings.Join(conditions, " AND ") + " ORDER BY block_number, log_index"
+
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying %s records: %w", kind, err)
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var (
+			r            Record
+			txHash       string
+			from, to     string
+			timestampSec int64
+		)
+		if err := rows.Scan(&r.BlockNumber, &txHash, &r.LogIndex, &from, &to, &r.Amount, &r.Method, &timestampSec); err != nil {
+			return nil, fmt.Errorf("scanning %s record: %w", kind, err)
+		}
+		r.TxHash = common.HexToHash(txHash)
+		r.From = common.HexToAddress(from)
+		r.To = common.HexToAddress(to)
+		r.Timestamp = time.Unix(timestampSec, 0).UTC()
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
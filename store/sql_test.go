@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func newTestSQLiteStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	s, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSQLStorePutIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSQLiteStore(t)
+
+	r := Record{
+		BlockNumber: 1,
+		TxHash:      common.HexToHash("0x01"),
+		LogIndex:    0,
+		From:        common.HexToAddress("0x000000000000000000000000000000000000aa"),
+		To:          common.HexToAddress("0x000000000000000000000000000000000000bb"),
+		Amount:      "1",
+		Method:      "Transfer",
+		Timestamp:   time.Unix(100, 0),
+	}
+
+	if err := s.PutTransfer(ctx, r); err != nil {
+		t.Fatalf("first PutTransfer: %v", err)
+	}
+	if err := s.PutTransfer(ctx, r); err != nil {
+		t.Fatalf("second PutTransfer (duplicate): %v", err)
+	}
+
+	got, err := s.Query(ctx, KindTransfer, Filter{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Query returned %d records after a duplicate insert, want 1: %+v", len(got), got)
+	}
+}
+
+func TestSQLStoreQueryFilters(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSQLiteStore(t)
+
+	alice := common.HexToAddress("0x000000000000000000000000000000000000aa")
+	bob := common.HexToAddress("0x000000000000000000000000000000000000bb")
+	carol := common.HexToAddress("0x000000000000000000000000000000000000cc")
+
+	records := []Record{
+		{BlockNumber: 1, TxHash: common.HexToHash("0x01"), From: alice, To: bob, Amount: "1", Method: "Transfer", Timestamp: time.Unix(100, 0)},
+		{BlockNumber: 2, TxHash: common.HexToHash("0x02"), From: bob, To: carol, Amount: "2", Method: "Transfer", Timestamp: time.Unix(200, 0)},
+		{BlockNumber: 3, TxHash: common.HexToHash("0x03"), From: alice, To: carol, Amount: "3", Method: "Transfer", Timestamp: time.Unix(300, 0)},
+	}
+	for _, r := range records {
+		if err := s.PutTransfer(ctx, r); err != nil {
+			t.Fatalf("PutTransfer: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []uint64
+	}{
+		{"no filter", Filter{}, []uint64{1, 2, 3}},
+		{"from alice", Filter{From: &alice}, []uint64{1, 3}},
+		{"to carol", Filter{To: &carol}, []uint64{2, 3}},
+		{"since block 2's timestamp", Filter{Since: time.Unix(200, 0)}, []uint64{2, 3}},
+		{"from alice to carol", Filter{From: &alice, To: &carol}, []uint64{3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Query(ctx, KindTransfer, tt.filter)
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Query returned %d records, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, r := range got {
+				if r.BlockNumber != tt.want[i] {
+					t.Errorf("record %d has BlockNumber %d, want %d", i, r.BlockNumber, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSQLStoreLastScannedBlock(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSQLiteStore(t)
+
+	if _, ok, err := s.LastScannedBlock(ctx); err != nil || ok {
+		t.Fatalf("LastScannedBlock on a fresh store = (ok=%v, err=%v), want (false, nil)", ok, err)
+	}
+
+	if err := s.SetLastScannedBlock(ctx, 42); err != nil {
+		t.Fatalf("SetLastScannedBlock: %v", err)
+	}
+	if err := s.SetLastScannedBlock(ctx, 43); err != nil {
+		t.Fatalf("SetLastScannedBlock (update): %v", err)
+	}
+
+	block, ok, err := s.LastScannedBlock(ctx)
+	if err != nil || !ok || block != 43 {
+		t.Fatalf("LastScannedBlock = (%d, %v, %v), want (43, true, nil)", block, ok, err)
+	}
+}
+
+func TestSQLStoreQueryKindIsolation(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSQLiteStore(t)
+
+	addr := common.HexToAddress("0x000000000000000000000000000000000000aa")
+	r := Record{BlockNumber: 1, TxHash: common.HexToHash("0x01"), From: addr, To: addr, Amount: "1", Method: "mint", Timestamp: time.Unix(100, 0)}
+
+	if err := s.PutMint(ctx, r); err != nil {
+		t.Fatalf("PutMint: %v", err)
+	}
+
+	transfers, err := s.Query(ctx, KindTransfer, Filter{})
+	if err != nil {
+		t.Fatalf("Query(transfer): %v", err)
+	}
+	if len(transfers) != 0 {
+		t.Errorf("Query(transfer) returned %d records after only a mint was inserted, want 0", len(transfers))
+	}
+}
@@ -0,0 +1,31 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteStore is a Store backed by a local SQLite database file. It's
+// the default for single-binary deployments that don't want to run a
+// separate database.
+type SQLiteStore struct {
+	*sqlStore
+}
+
+// NewSQLiteStore opens (creating if necessary) the SQLite database at
+// path and ensures its schema is up to date.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite database %s: %w", path, err)
+	}
+
+	base, err := newSQLStore(db, questionPlaceholder)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteStore{sqlStore: base}, nil
+}
@@ -0,0 +1,62 @@
+// Package store persists decoded mints, distributions, and transfers
+// so the indexer's output survives past a single run, and lets the
+// scanner resume from where it left off instead of re-walking its
+// whole window every time it starts.
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Record is a single decoded event: a mint, a distribution, or a
+// transfer. Amount is kept as an exact base-10 decimal string (see
+// package units) rather than a float, since the point of persisting
+// this data is accounting, and floats lose precision at scale.
+type Record struct {
+	BlockNumber uint64
+	TxHash      common.Hash
+	LogIndex    uint
+
+	From common.Address
+	To   common.Address
+
+	Amount string // exact decimal string, e.g. "1234.5"
+	Method string // decoded method or event name, e.g. "mint", "Transfer"
+
+	Timestamp time.Time
+}
+
+// Filter narrows a Query. A zero Filter matches everything.
+type Filter struct {
+	From  *common.Address
+	To    *common.Address
+	Since time.Time
+}
+
+// Store is the persistence interface the scanner, subscriber, and HTTP
+// API are written against. Implementations: MemoryStore (tests),
+// SQLiteStore, PostgresStore.
+type Store interface {
+	PutMint(ctx context.Context, r Record) error
+	PutDistribution(ctx context.Context, r Record) error
+	PutTransfer(ctx context.Context, r Record) error
+
+	// LastScannedBlock returns the last block number a prior scan
+	// completed through, and false if nothing has been scanned yet.
+	LastScannedBlock(ctx context.Context) (uint64, bool, error)
+	SetLastScannedBlock(ctx context.Context, block uint64) error
+
+	Query(ctx context.Context, kind string, filter Filter) ([]Record, error)
+
+	Close() error
+}
+
+// Kinds of record a Query can be restricted to.
+const (
+	KindMint         = "mint"
+	KindDistribution = "distribution"
+	KindTransfer     = "transfer"
+)
@@ -0,0 +1,268 @@
+// Package subscriber streams logs from an Ethereum node in real time
+// over eth_subscribe, instead of the one-shot historical scans done by
+// package scanner. It buffers incoming logs for a configurable number
+// of confirmations before handing them to the caller, and detects
+// reorgs two ways: the node may flag an individual log as removed
+// directly on the logs subscription, and newHeads is watched for
+// parent-hash mismatches and same-height replacements so it can
+// retract and replace logs affected by a reorg either way.
+package subscriber
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Config describes what to subscribe to and how many confirmations to
+// wait before treating a log as final.
+type Config struct {
+	Client *rpc.Client // must be dialed against a ws:// or wss:// endpoint
+
+	Addresses []common.Address
+	Topics    []common.Hash
+
+	// Confirmations is the number of blocks to wait, past the block a
+	// log appeared in, before emitting it. 0 emits it on the first
+	// newHeads header at or past its block.
+	Confirmations uint64
+}
+
+// Event is a single log delivered to the caller. Removed is set when
+// this event retracts a log emitted earlier for a block that was
+// reorged out; callers should treat it the same way they would
+// eth_subscribe's own "removed" flag.
+type Event struct {
+	Log     types.Log
+	Removed bool
+}
+
+// Subscriber streams confirmed log events from Config.Addresses and
+// Config.Topics, reorg-correcting as it goes.
+type Subscriber struct {
+	cfg    Config
+	events chan Event
+	errc   chan error
+	cancel context.CancelFunc
+}
+
+// New opens a "logs" and a "newHeads" subscription against cfg.Client
+// and starts streaming confirmed events. Call Close when done to tear
+// down both subscriptions.
+func New(ctx context.Context, cfg Config) (*Subscriber, error) {
+	sctx, cancel := context.WithCancel(ctx)
+
+	logsCh := make(chan types.Log, 256)
+	logsSub, err := cfg.Client.EthSubscribe(sctx, logsCh, "logs", map[string]interface{}{
+		"address": cfg.Addresses,
+		"topics":  [][]common.Hash{cfg.Topics},
+	})
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("subscribing to logs: %w", err)
+	}
+
+	headsCh := make(chan *types.Header, 16)
+	headsSub, err := cfg.Client.EthSubscribe(sctx, headsCh, "newHeads")
+	if err != nil {
+		logsSub.Unsubscribe()
+		cancel()
+		return nil, fmt.Errorf("subscribing to newHeads: %w", err)
+	}
+
+	s := &Subscriber{
+		cfg:    cfg,
+		events: make(chan Event, 256),
+		errc:   make(chan error, 1),
+		cancel: cancel,
+	}
+	go s.run(sctx, logsCh, logsSub, headsCh, headsSub)
+	return s, nil
+}
+
+// Events returns the channel confirmed (and corrected) log events are
+// delivered on. It is closed when the subscriber stops.
+func (s *Subscriber) Events() <-chan Event {
+	return s.events
+}
+
+// Err returns the error that caused the subscriber to stop, if any. It
+// should be read after Events() is closed.
+func (s *Subscriber) Err() error {
+	select {
+	case err := <-s.errc:
+		return err
+	default:
+		return nil
+	}
+}
+
+// Close tears down both subscriptions and stops the run loop.
+func (s *Subscriber) Close() {
+	s.cancel()
+}
+
+// evictionWindow bounds how long a confirmed block is still considered
+// reorg-able. Blocks more than this many confirmations behind head have
+// their emitted/knownHash entries dropped so a long-running --follow
+// process doesn't grow these maps without bound.
+const evictionWindow = 256
+
+// run buffers incoming logs by block number in pending until that
+// block has cfg.Confirmations confirmations, then emits them. It keeps
+// a short history of already-emitted logs in emitted so a late reorg
+// can still be corrected with Removed events; entries older than
+// evictionWindow confirmations are pruned so the history stays short.
+func (s *Subscriber) run(ctx context.Context, logsCh chan types.Log, logsSub *rpc.ClientSubscription, headsCh chan *types.Header, headsSub *rpc.ClientSubscription) {
+	defer close(s.events)
+	defer logsSub.Unsubscribe()
+	defer headsSub.Unsubscribe()
+
+	pending := map[uint64][]types.Log{}
+	emitted := map[uint64][]types.Log{}
+	knownHash := map[uint64]common.Hash{}
+	var head uint64
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case err := <-logsSub.Err():
+			s.errc <- fmt.Errorf("logs subscription: %w", err)
+			return
+
+		case err := <-headsSub.Err():
+			s.errc <- fmt.Errorf("newHeads subscription: %w", err)
+			return
+
+		case l := <-logsCh:
+			if l.Removed {
+				s.retractLog(pending, emitted, l)
+			} else {
+				pending[l.BlockNumber] = append(pending[l.BlockNumber], l)
+			}
+
+		case h := <-headsCh:
+			number := h.Number.Uint64()
+			if parent, ok := knownHash[number-1]; ok && parent != h.ParentHash {
+				if err := s.handleReorg(ctx, number-1, pending, emitted); err != nil {
+					s.errc <- fmt.Errorf("handling reorg at block %d: %w", number-1, err)
+					return
+				}
+			}
+			if prev, ok := knownHash[number]; ok && prev != h.Hash() {
+				if err := s.handleReorg(ctx, number, pending, emitted); err != nil {
+					s.errc <- fmt.Errorf("handling reorg at block %d: %w", number, err)
+					return
+				}
+			}
+			knownHash[number] = h.Hash()
+			head = number
+			s.emitConfirmed(pending, emitted, head)
+			evictOld(emitted, knownHash, head)
+		}
+	}
+}
+
+// evictOld drops emitted and knownHash entries for blocks more than
+// evictionWindow confirmations behind head; anything that old is not
+// going to be reorged out, so there is no reason to keep remembering it.
+func evictOld(emitted map[uint64][]types.Log, knownHash map[uint64]common.Hash, head uint64) {
+	if head <= evictionWindow {
+		return
+	}
+	cutoff := head - evictionWindow
+	for blockNumber := range emitted {
+		if blockNumber < cutoff {
+			delete(emitted, blockNumber)
+		}
+	}
+	for blockNumber := range knownHash {
+		if blockNumber < cutoff {
+			delete(knownHash, blockNumber)
+		}
+	}
+}
+
+// emitConfirmed moves every block in pending that now has
+// cfg.Confirmations confirmations into emitted and sends its logs out.
+func (s *Subscriber) emitConfirmed(pending, emitted map[uint64][]types.Log, head uint64) {
+	for blockNumber, logs := range pending {
+		if blockNumber+s.cfg.Confirmations > head {
+			continue
+		}
+		for _, l := range logs {
+			s.events <- Event{Log: l}
+		}
+		emitted[blockNumber] = logs
+		delete(pending, blockNumber)
+	}
+}
+
+// handleReorg is called when a newHeads header's parent hash no longer
+// matches what we last saw for that block number. It retracts any logs
+// already emitted for blockNumber, drops any still buffered in
+// pending, and replaces both with a fresh eth_getLogs read.
+func (s *Subscriber) handleReorg(ctx context.Context, blockNumber uint64, pending, emitted map[uint64][]types.Log) error {
+	for _, l := range emitted[blockNumber] {
+		s.events <- Event{Log: l, Removed: true}
+	}
+	delete(emitted, blockNumber)
+	delete(pending, blockNumber)
+
+	fresh, err := s.getLogs(ctx, blockNumber)
+	if err != nil {
+		return err
+	}
+	if len(fresh) > 0 {
+		pending[blockNumber] = fresh
+	}
+	return nil
+}
+
+// retractLog handles a log the node itself has already flagged as
+// removed (l.Removed): if it's still buffered in pending it's simply
+// dropped, and if it was already emitted as confirmed it's retracted
+// with a Removed event, same as handleReorg does for a header-detected
+// reorg.
+func (s *Subscriber) retractLog(pending, emitted map[uint64][]types.Log, l types.Log) {
+	if removeLog(pending, l) {
+		return
+	}
+	if removeLog(emitted, l) {
+		s.events <- Event{Log: l, Removed: true}
+	}
+}
+
+// removeLog deletes the log in logs matching target's (BlockNumber,
+// TxHash, Index) and reports whether it found one.
+func removeLog(logs map[uint64][]types.Log, target types.Log) bool {
+	bucket := logs[target.BlockNumber]
+	for i, l := range bucket {
+		if l.TxHash == target.TxHash && l.Index == target.Index {
+			logs[target.BlockNumber] = append(bucket[:i], bucket[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Subscriber) getLogs(ctx context.Context, blockNumber uint64) ([]types.Log, error) {
+	filter := map[string]interface{}{
+		"fromBlock": hexutil.EncodeUint64(blockNumber),
+		"toBlock":   hexutil.EncodeUint64(blockNumber),
+		"address":   s.cfg.Addresses,
+		"topics":    [][]common.Hash{s.cfg.Topics},
+	}
+
+	var logs []types.Log
+	if err := s.cfg.Client.CallContext(ctx, &logs, "eth_getLogs", filter); err != nil {
+		return nil, fmt.Errorf("eth_getLogs: %w", err)
+	}
+	return logs, nil
+}
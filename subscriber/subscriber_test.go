@@ -0,0 +1,217 @@
+package subscriber
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// newTestRPCClient starts a local JSON-RPC server that answers every
+// call with result, so eth_getLogs can be exercised offline.
+func newTestRPCClient(t *testing.T, result string) *rpc.Client {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("decoding request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, result)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := rpc.DialContext(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("dialing test rpc server: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestEmitConfirmed(t *testing.T) {
+	s := &Subscriber{cfg: Config{Confirmations: 2}, events: make(chan Event, 10)}
+
+	log5 := types.Log{BlockNumber: 5, TxHash: common.HexToHash("0x05")}
+	log8 := types.Log{BlockNumber: 8, TxHash: common.HexToHash("0x08")}
+	pending := map[uint64][]types.Log{5: {log5}, 8: {log8}}
+	emitted := map[uint64][]types.Log{}
+
+	// head=7: block 5 has 2 confirmations (7-5=2), block 8 has none yet.
+	s.emitConfirmed(pending, emitted, 7)
+
+	if _, ok := pending[5]; ok {
+		t.Error("pending[5] should have been confirmed and removed")
+	}
+	if _, ok := pending[8]; !ok {
+		t.Error("pending[8] should still be unconfirmed")
+	}
+	if got := emitted[5]; len(got) != 1 || got[0].TxHash != log5.TxHash {
+		t.Errorf("emitted[5] = %+v, want [%+v]", got, log5)
+	}
+
+	select {
+	case ev := <-s.events:
+		if ev.Removed || ev.Log.TxHash != log5.TxHash {
+			t.Errorf("emitted event = %+v, want a non-removed event for log5", ev)
+		}
+	default:
+		t.Fatal("expected a confirmed event for block 5")
+	}
+}
+
+func TestEvictOld(t *testing.T) {
+	emitted := map[uint64][]types.Log{100: {{}}, 500: {{}}, 743: {{}}, 744: {{}}}
+	knownHash := map[uint64]common.Hash{100: {}, 500: {}, 743: {}, 744: {}}
+
+	// head=1000, evictionWindow=256 -> cutoff=744. Anything below 744 goes.
+	evictOld(emitted, knownHash, 1000)
+
+	for _, blockNumber := range []uint64{100, 500, 743} {
+		if _, ok := emitted[blockNumber]; ok {
+			t.Errorf("emitted[%d] should have been evicted", blockNumber)
+		}
+		if _, ok := knownHash[blockNumber]; ok {
+			t.Errorf("knownHash[%d] should have been evicted", blockNumber)
+		}
+	}
+	if _, ok := emitted[744]; !ok {
+		t.Error("emitted[744] is within the eviction window and should remain")
+	}
+}
+
+func TestEvictOldBelowWindowIsNoop(t *testing.T) {
+	emitted := map[uint64][]types.Log{10: {{}}}
+	knownHash := map[uint64]common.Hash{10: {}}
+
+	evictOld(emitted, knownHash, 50) // head < evictionWindow
+
+	if _, ok := emitted[10]; !ok {
+		t.Error("emitted[10] should not be evicted while head is still below evictionWindow")
+	}
+}
+
+func TestRemoveLog(t *testing.T) {
+	target := types.Log{BlockNumber: 5, TxHash: common.HexToHash("0xaa"), Index: 1}
+	other := types.Log{BlockNumber: 5, TxHash: common.HexToHash("0xbb"), Index: 0}
+
+	logs := map[uint64][]types.Log{5: {other, target}}
+	if !removeLog(logs, target) {
+		t.Fatal("expected removeLog to find the target log")
+	}
+	if got := logs[5]; len(got) != 1 || got[0].TxHash != other.TxHash {
+		t.Errorf("logs[5] = %+v, want just [%+v]", got, other)
+	}
+
+	if removeLog(logs, target) {
+		t.Error("removeLog should report false for a log that isn't there")
+	}
+}
+
+func TestRetractLogDropsFromPending(t *testing.T) {
+	s := &Subscriber{events: make(chan Event, 10)}
+
+	l := types.Log{BlockNumber: 5, TxHash: common.HexToHash("0xaa"), Removed: true}
+	pending := map[uint64][]types.Log{5: {l}}
+	emitted := map[uint64][]types.Log{}
+
+	s.retractLog(pending, emitted, l)
+
+	if got := pending[5]; len(got) != 0 {
+		t.Errorf("pending[5] = %+v, want it dropped", got)
+	}
+	select {
+	case ev := <-s.events:
+		t.Errorf("dropping an unconfirmed pending log should not emit an event, got %+v", ev)
+	default:
+	}
+}
+
+func TestRetractLogRetractsFromEmitted(t *testing.T) {
+	s := &Subscriber{events: make(chan Event, 10)}
+
+	l := types.Log{BlockNumber: 5, TxHash: common.HexToHash("0xaa"), Removed: true}
+	pending := map[uint64][]types.Log{}
+	emitted := map[uint64][]types.Log{5: {l}}
+
+	s.retractLog(pending, emitted, l)
+
+	if got := emitted[5]; len(got) != 0 {
+		t.Errorf("emitted[5] = %+v, want it dropped", got)
+	}
+	select {
+	case ev := <-s.events:
+		if !ev.Removed || ev.Log.TxHash != l.TxHash {
+			t.Errorf("event = %+v, want a Removed event for %+v", ev, l)
+		}
+	default:
+		t.Fatal("expected a Removed event retracting the previously emitted log")
+	}
+}
+
+func TestHandleReorgRetractsEmittedAndReplacesWithFreshLogs(t *testing.T) {
+	fresh := types.Log{BlockNumber: 10, TxHash: common.HexToHash("0xaa"), Index: 0, Topics: []common.Hash{common.HexToHash("0x01")}}
+	result, err := json.Marshal([]types.Log{fresh})
+	if err != nil {
+		t.Fatalf("marshaling fixture: %v", err)
+	}
+
+	s := &Subscriber{
+		cfg:    Config{Client: newTestRPCClient(t, string(result))},
+		events: make(chan Event, 10),
+	}
+
+	stale := types.Log{BlockNumber: 10, TxHash: common.HexToHash("0xbb"), Index: 0}
+	unconfirmed := types.Log{BlockNumber: 10, TxHash: common.HexToHash("0xcc"), Index: 1}
+	emitted := map[uint64][]types.Log{10: {stale}}
+	pending := map[uint64][]types.Log{10: {unconfirmed}}
+
+	if err := s.handleReorg(context.Background(), 10, pending, emitted); err != nil {
+		t.Fatalf("handleReorg: %v", err)
+	}
+
+	if _, ok := emitted[10]; ok {
+		t.Error("emitted[10] should have been cleared")
+	}
+
+	select {
+	case ev := <-s.events:
+		if !ev.Removed || ev.Log.TxHash != stale.TxHash {
+			t.Errorf("event = %+v, want a Removed event for the stale emitted log", ev)
+		}
+	default:
+		t.Fatal("expected a Removed event for the retracted emitted log")
+	}
+
+	if got := pending[10]; len(got) != 1 || got[0].TxHash != fresh.TxHash {
+		t.Errorf("pending[10] = %+v, want just the fresh log from eth_getLogs", got)
+	}
+}
+
+func TestHandleReorgWithNoFreshLogsLeavesPendingEmpty(t *testing.T) {
+	s := &Subscriber{
+		cfg:    Config{Client: newTestRPCClient(t, "[]")},
+		events: make(chan Event, 10),
+	}
+
+	pending := map[uint64][]types.Log{10: {{BlockNumber: 10}}}
+	emitted := map[uint64][]types.Log{}
+
+	if err := s.handleReorg(context.Background(), 10, pending, emitted); err != nil {
+		t.Fatalf("handleReorg: %v", err)
+	}
+	if _, ok := pending[10]; ok {
+		t.Error("pending[10] should not be set when eth_getLogs returns nothing")
+	}
+}
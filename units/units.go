@@ -0,0 +1,38 @@
+// Package units formats raw token amounts (base units, as returned by
+// an ABI-decoded *big.Int) into human-readable decimal strings without
+// going through floating point, which loses precision for large
+// amounts.
+package units
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Format renders amount/10^decimals as an exact base-10 decimal
+// string: the integer and fractional parts are produced by
+// big.Int.QuoRem against 10^decimals, the fractional part is
+// left-padded to decimals digits, and trailing zeros are trimmed. A
+// nil amount formats as "0".
+func Format(amount *big.Int, decimals uint8) string {
+	if amount == nil {
+		return "0"
+	}
+
+	if decimals == 0 {
+		return amount.String()
+	}
+
+	divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	whole, frac := new(big.Int).QuoRem(amount, divisor, new(big.Int))
+
+	fracStr := frac.Abs(frac).String()
+	if pad := int(decimals) - len(fracStr); pad > 0 {
+		fracStr = strings.Repeat("0", pad) + fracStr
+	}
+	fracStr = strings.TrimRight(fracStr, "0")
+	if fracStr == "" {
+		return whole.String()
+	}
+	return whole.String() + "." + fracStr
+}
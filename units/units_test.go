@@ -0,0 +1,38 @@
+package units
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	bigPow := func(exp int64) *big.Int {
+		return new(big.Int).Exp(big.NewInt(10), big.NewInt(exp), nil)
+	}
+
+	tests := []struct {
+		name     string
+		amount   *big.Int
+		decimals uint8
+		want     string
+	}{
+		{"nil amount", nil, 6, "0"},
+		{"zero decimals", big.NewInt(12345), 0, "12345"},
+		{"zero amount", big.NewInt(0), 6, "0"},
+		{"amount less than one unit", big.NewInt(500), 6, "0.0005"},
+		{"exactly one unit", big.NewInt(1_000_000), 6, "1"},
+		{"exactly divisible, multiple units", big.NewInt(42_000_000), 6, "42"},
+		{"trailing zeros trimmed", big.NewInt(1_230_000), 6, "1.23"},
+		{"fractional needs left padding", big.NewInt(1_000_001), 6, "1.000001"},
+		{"10^30 scale value", new(big.Int).Add(bigPow(30), big.NewInt(500_000)), 6, "1000000000000000000000000.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Format(tt.amount, tt.decimals)
+			if got != tt.want {
+				t.Errorf("Format(%v, %d) = %q, want %q", tt.amount, tt.decimals, got, tt.want)
+			}
+		})
+	}
+}